@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Backup describes one Transaction's snapshot, for `inspect backups list`.
+type Backup struct {
+	ID    string   `json:"id"`
+	Files []string `json:"files"`
+}
+
+// ListBackups returns every transaction snapshot found under backupRoot,
+// most recently created last.
+func ListBackups() ([]Backup, error) {
+	ids, err := ioutil.ReadDir(backupRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return ids[i].ModTime().Before(ids[j].ModTime()) })
+
+	var backups []Backup
+	for _, id := range ids {
+		if !id.IsDir() {
+			continue
+		}
+		var files []string
+		err := filepath.Walk(filepath.Join(backupRoot, id.Name()), func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(filepath.Join(backupRoot, id.Name()), path)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		backups = append(backups, Backup{ID: id.Name(), Files: files})
+	}
+	return backups, nil
+}
+
+// RestoreBackup copies every file snapshotted under transaction id back to
+// its original location, via WriteFileFunc.
+func RestoreBackup(id string) error {
+	backupDir := filepath.Join(backupRoot, id)
+	var restoreErr error
+	err := filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(backupDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := ReadFileFunc(path)
+		if err != nil {
+			return err
+		}
+		if err := WriteFileFunc(rel, data); err != nil {
+			restoreErr = err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return restoreErr
+}
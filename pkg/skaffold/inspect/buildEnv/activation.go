@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/inspect"
+	v1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+)
+
+// statusCode implements fmt.Stringer so a plain string can satisfy
+// inspect.OutputInspectError's status-code lookup.
+type statusCode string
+
+func (s statusCode) String() string { return string(s) }
+
+// profileActivationConflictError is returned when a build-env subcommand
+// would change the activation conditions of a profile that already has
+// different ones, and opts.FailOnActivationConflict is set.
+type profileActivationConflictError struct {
+	profile string
+}
+
+func (e *profileActivationConflictError) Error() string {
+	return fmt.Sprintf("profile %q already has different activation conditions; rerun without --fail-on-activation-conflict to merge them", e.profile)
+}
+
+func (e *profileActivationConflictError) StatusCode() fmt.Stringer {
+	return statusCode("PROFILE_ACTIVATION_CONFLICT")
+}
+
+// buildActivations materializes opts' activation fields into the
+// v1.Activation entries a hand-written profile would use. A single
+// kubeContext/command pair is ANDed with each requested env var, and
+// multiple env vars become separate (ORed) activation entries, since
+// v1.Activation only carries one "key=value" env expression each.
+func buildActivations(opts inspect.BuildEnvOptions) []v1.Activation {
+	if len(opts.ActivationEnv) == 0 {
+		if opts.ActivationKubeContext == "" && opts.ActivationCommand == "" {
+			return nil
+		}
+		return []v1.Activation{{KubeContext: opts.ActivationKubeContext, Command: opts.ActivationCommand}}
+	}
+
+	keys := make([]string, 0, len(opts.ActivationEnv))
+	for k := range opts.ActivationEnv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	activations := make([]v1.Activation, 0, len(keys))
+	for _, k := range keys {
+		activations = append(activations, v1.Activation{
+			Env:         fmt.Sprintf("%s=%s", k, opts.ActivationEnv[k]),
+			KubeContext: opts.ActivationKubeContext,
+			Command:     opts.ActivationCommand,
+		})
+	}
+	return activations
+}
+
+// applyProfileActivation sets p's activation conditions from opts. For a
+// brand-new profile it's a plain assignment. For an existing profile with no
+// activation yet, or one that already matches, it's also a no-op beyond
+// assignment/confirmation. Otherwise it merges the two sets of conditions,
+// unless opts.FailOnActivationConflict asks to reject the change instead.
+func applyProfileActivation(p *v1.Profile, opts inspect.BuildEnvOptions, isNew bool) error {
+	requested := buildActivations(opts)
+	if len(requested) == 0 {
+		return nil
+	}
+	if isNew || len(p.Activation) == 0 || activationsEqual(p.Activation, requested) {
+		p.Activation = requested
+		return nil
+	}
+	if opts.FailOnActivationConflict {
+		return &profileActivationConflictError{profile: p.Name}
+	}
+	p.Activation = mergeActivations(p.Activation, requested)
+	return nil
+}
+
+func activationsEqual(a, b []v1.Activation) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// mergeActivations appends every entry in b not already present in a.
+func mergeActivations(a, b []v1.Activation) []v1.Activation {
+	merged := append([]v1.Activation{}, a...)
+	for _, candidate := range b {
+		found := false
+		for _, existing := range merged {
+			if reflect.DeepEqual(existing, candidate) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, candidate)
+		}
+	}
+	return merged
+}
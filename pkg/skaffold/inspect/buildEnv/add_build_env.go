@@ -0,0 +1,214 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/inspect"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/parser"
+	v1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+)
+
+// docSeparator is the separator MarshalWithSeparator places between the YAML
+// documents of a single multi-config file.
+const docSeparator = "\n---\n"
+
+// AddBuildEnv injects spec into either the default build config of every
+// selected, root-level module (when opts.BuildEnvOptions.Profile is empty),
+// or into the named profile of every selected module (creating the profile
+// when it doesn't exist yet). Modules that depend on another module
+// receiving the same named profile get that dependency added to their
+// `activeProfiles` list, so the profile cascades the same way a hand-written
+// one would.
+//
+// Only the selected configs are mutated, but a whole source file is
+// rewritten as soon as one of its configs changes, so any sibling config in
+// that file that wasn't selected is read fresh off disk and written back
+// untouched.
+//
+// When opts.DryRun is set, no file is written: a preview in opts.DiffFormat
+// is emitted to out instead, and AddBuildEnv returns ErrPendingChanges if
+// any file would have changed.
+//
+// Writes go through an inspect.Transaction: if a later file fails to write,
+// every file already written during this call is rolled back to its
+// original content, so a failure never leaves the tree half-modified.
+func AddBuildEnv(ctx context.Context, out io.Writer, opts inspect.Options, spec BuildEnvSpec) error {
+	configSet, err := inspect.ConfigSetFunc(config.SkaffoldOptions{ConfigurationFilter: opts.Modules})
+	if err != nil {
+		return inspect.OutputInspectError(out, err)
+	}
+
+	profile := opts.BuildEnvOptions.Profile
+	var toMutate []*parser.SkaffoldConfigEntry
+	for _, entry := range configSet {
+		if profile == "" && !entry.IsRootConfig {
+			continue
+		}
+		toMutate = append(toMutate, entry)
+	}
+
+	var tx *inspect.Transaction
+	if !opts.DryRun {
+		tx = inspect.NewTransaction()
+	}
+
+	// Snapshot the untouched content of every file we're about to change
+	// before mutating anything in memory, so a dry-run diff (and the
+	// reconstruction of any sibling config sharing the same file) always
+	// compares against what's actually on disk.
+	before := map[string][]byte{}
+	for _, entry := range toMutate {
+		if _, ok := before[entry.SourceFile]; ok {
+			continue
+		}
+		var raw []byte
+		var err error
+		if tx != nil {
+			raw, err = tx.Read(entry.SourceFile)
+		} else {
+			raw, err = inspect.ReadFileFunc(entry.SourceFile)
+		}
+		if err != nil {
+			return inspect.OutputInspectError(out, err)
+		}
+		before[entry.SourceFile] = raw
+	}
+
+	mutated := map[string]map[int]*v1.SkaffoldConfig{}
+	for _, entry := range toMutate {
+		cfg := entry.SkaffoldConfig
+		if profile == "" {
+			spec.Apply(&cfg.Pipeline.Build)
+		} else {
+			p, isNew := findOrCreateProfile(cfg, profile)
+			spec.Apply(&p.Pipeline.Build)
+			if err := applyProfileActivation(p, opts.BuildEnvOptions, isNew); err != nil {
+				return inspect.OutputInspectError(out, err)
+			}
+			propagateActiveProfile(cfg, profile)
+		}
+
+		if mutated[entry.SourceFile] == nil {
+			mutated[entry.SourceFile] = map[int]*v1.SkaffoldConfig{}
+		}
+		mutated[entry.SourceFile][entry.SourceIndex] = cfg
+	}
+
+	files := make([]string, 0, len(mutated))
+	for file := range mutated {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	pending := false
+	for _, file := range files {
+		docs, err := splitDocuments(before[file])
+		if err != nil {
+			return inspect.OutputInspectError(out, err)
+		}
+		for idx, cfg := range mutated[file] {
+			docs[idx] = cfg
+		}
+
+		data, err := yaml.MarshalWithSeparator(docs)
+		if err != nil {
+			return inspect.OutputInspectError(out, err)
+		}
+
+		if opts.DryRun {
+			if string(before[file]) != string(data) {
+				pending = true
+			}
+			if err := writeDryRunPreview(out, opts.DiffFormat, file, before[file], data); err != nil {
+				return inspect.OutputInspectError(out, err)
+			}
+			continue
+		}
+		if err := tx.Write(file, data); err != nil {
+			return inspect.OutputInspectError(out, err)
+		}
+	}
+	if opts.DryRun {
+		if pending {
+			return inspect.ErrPendingChanges
+		}
+		return nil
+	}
+	if err := tx.Commit(); err != nil {
+		return inspect.OutputInspectError(out, err)
+	}
+	return nil
+}
+
+// splitDocuments parses a multi-document skaffold.yaml file, in the order
+// its documents appear.
+func splitDocuments(raw []byte) ([]*v1.SkaffoldConfig, error) {
+	chunks := bytes.Split(raw, []byte(docSeparator))
+	docs := make([]*v1.SkaffoldConfig, len(chunks))
+	for i, chunk := range chunks {
+		cfg := &v1.SkaffoldConfig{}
+		if err := yaml.Unmarshal(chunk, cfg); err != nil {
+			return nil, err
+		}
+		docs[i] = cfg
+	}
+	return docs, nil
+}
+
+// findOrCreateProfile returns the profile named name on cfg and whether it
+// already existed, appending a new bare profile (just the name) if not.
+func findOrCreateProfile(cfg *v1.SkaffoldConfig, name string) (*v1.Profile, bool) {
+	for i := range cfg.Profiles {
+		if cfg.Profiles[i].Name == name {
+			return &cfg.Profiles[i], false
+		}
+	}
+	cfg.Profiles = append(cfg.Profiles, v1.Profile{Name: name})
+	return &cfg.Profiles[len(cfg.Profiles)-1], true
+}
+
+// propagateActiveProfile ensures every dependency of cfg activates profile
+// when cfg's own copy of that profile is active, merging into any
+// `activeProfiles` entry that already exists for it.
+func propagateActiveProfile(cfg *v1.SkaffoldConfig, profile string) {
+	for i := range cfg.Dependencies {
+		dep := &cfg.Dependencies[i]
+
+		var existing *v1.ProfileDependency
+		for j := range dep.ActiveProfiles {
+			if dep.ActiveProfiles[j].Name == profile {
+				existing = &dep.ActiveProfiles[j]
+				break
+			}
+		}
+		if existing == nil {
+			dep.ActiveProfiles = append(dep.ActiveProfiles, v1.ProfileDependency{Name: profile, ActivatedBy: []string{profile}})
+			continue
+		}
+		if !util.StrSliceContains(existing.ActivatedBy, profile) {
+			existing.ActivatedBy = append(existing.ActivatedBy, profile)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"context"
+	"io"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/inspect"
+)
+
+// AddGcbBuildEnv adds a Google Cloud Build environment to the selected
+// modules. It's kept as a thin, GCB-specific entry point over AddBuildEnv so
+// that existing callers and flags don't have to change.
+func AddGcbBuildEnv(ctx context.Context, out io.Writer, opts inspect.Options) error {
+	return AddBuildEnv(ctx, out, opts, NewGcbSpec(opts.BuildEnvOptions))
+}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
@@ -40,13 +41,17 @@ const (
 
 func TestAddGcbBuildEnv(t *testing.T) {
 	tests := []struct {
-		description     string
-		profile         string
-		modules         []string
-		buildEnvOpts    inspect.BuildEnvOptions
-		expectedConfigs []string
-		err             error
-		expectedErrMsg  string
+		description        string
+		profile            string
+		modules            []string
+		buildEnvOpts       inspect.BuildEnvOptions
+		existingActivation []v1.Activation
+		expectedConfigs    []string
+		err                error
+		expectedErrMsg     string
+		dryRun             bool
+		expectPending      bool
+		expectDiffContains string
 	}{
 		{
 			description:  "add to default pipeline",
@@ -215,6 +220,204 @@ profiles:
 `,
 			},
 		},
+		{
+			description:  "add to new profile with kubeContext and command activation",
+			buildEnvOpts: inspect.BuildEnvOptions{ProjectID: "project1", DiskSizeGb: 2, MachineType: "machine1", Timeout: "128", Concurrency: 2, Profile: "p2", ActivationKubeContext: "gke_test", ActivationCommand: "build"},
+			expectedConfigs: []string{
+				`apiVersion: ""
+kind: ""
+metadata:
+  name: cfg1_0
+build:
+  local: {}
+profiles:
+- name: p1
+  build:
+    cluster: {}
+- name: p2
+  activation:
+  - kubeContext: gke_test
+    command: build
+  build:
+    googleCloudBuild:
+      projectId: project1
+      diskSizeGb: 2
+      machineType: machine1
+      timeout: "128"
+      concurrency: 2
+---
+apiVersion: ""
+kind: ""
+metadata:
+  name: cfg1_1
+requires:
+- path: path/to/cfg2
+  activeProfiles:
+  - name: p2
+    activatedBy:
+    - p2
+build:
+  local: {}
+profiles:
+- name: p1
+  build:
+    cluster: {}
+- name: p2
+  activation:
+  - kubeContext: gke_test
+    command: build
+  build:
+    googleCloudBuild:
+      projectId: project1
+      diskSizeGb: 2
+      machineType: machine1
+      timeout: "128"
+      concurrency: 2
+`, `apiVersion: ""
+kind: ""
+metadata:
+  name: cfg2
+build:
+  googleCloudBuild: {}
+profiles:
+- name: p1
+  build:
+    local: {}
+- name: p2
+  activation:
+  - kubeContext: gke_test
+    command: build
+  build:
+    googleCloudBuild:
+      projectId: project1
+      diskSizeGb: 2
+      machineType: machine1
+      timeout: "128"
+      concurrency: 2
+`,
+			},
+		},
+		{
+			description:  "add to new profile with env activation",
+			buildEnvOpts: inspect.BuildEnvOptions{ProjectID: "project1", DiskSizeGb: 2, MachineType: "machine1", Timeout: "128", Concurrency: 2, Profile: "p2", ActivationEnv: map[string]string{"FOO": "bar"}},
+			expectedConfigs: []string{
+				`apiVersion: ""
+kind: ""
+metadata:
+  name: cfg1_0
+build:
+  local: {}
+profiles:
+- name: p1
+  build:
+    cluster: {}
+- name: p2
+  activation:
+  - env: FOO=bar
+  build:
+    googleCloudBuild:
+      projectId: project1
+      diskSizeGb: 2
+      machineType: machine1
+      timeout: "128"
+      concurrency: 2
+---
+apiVersion: ""
+kind: ""
+metadata:
+  name: cfg1_1
+requires:
+- path: path/to/cfg2
+  activeProfiles:
+  - name: p2
+    activatedBy:
+    - p2
+build:
+  local: {}
+profiles:
+- name: p1
+  build:
+    cluster: {}
+- name: p2
+  activation:
+  - env: FOO=bar
+  build:
+    googleCloudBuild:
+      projectId: project1
+      diskSizeGb: 2
+      machineType: machine1
+      timeout: "128"
+      concurrency: 2
+`, `apiVersion: ""
+kind: ""
+metadata:
+  name: cfg2
+build:
+  googleCloudBuild: {}
+profiles:
+- name: p1
+  build:
+    local: {}
+- name: p2
+  activation:
+  - env: FOO=bar
+  build:
+    googleCloudBuild:
+      projectId: project1
+      diskSizeGb: 2
+      machineType: machine1
+      timeout: "128"
+      concurrency: 2
+`,
+			},
+		},
+		{
+			description:        "add to existing profile with conflicting activation merges by default",
+			buildEnvOpts:       inspect.BuildEnvOptions{ProjectID: "project1", DiskSizeGb: 2, MachineType: "machine1", Timeout: "128", Concurrency: 2, Profile: "p1", ActivationKubeContext: "gke_test"},
+			modules:            []string{"cfg1_0"},
+			existingActivation: []v1.Activation{{Command: "build"}},
+			expectedConfigs: []string{
+				`apiVersion: ""
+kind: ""
+metadata:
+  name: cfg1_0
+build:
+  local: {}
+profiles:
+- name: p1
+  activation:
+  - command: build
+  - kubeContext: gke_test
+  build:
+    googleCloudBuild:
+      projectId: project1
+      diskSizeGb: 2
+      machineType: machine1
+      timeout: "128"
+      concurrency: 2
+---
+apiVersion: ""
+kind: ""
+metadata:
+  name: cfg1_1
+requires:
+- path: path/to/cfg2
+build:
+  local: {}
+profiles:
+- name: p1
+  build:
+    cluster: {}
+`, ``,
+			},
+		},
+		{
+			description:        "add to existing profile with conflicting activation fails when requested",
+			buildEnvOpts:       inspect.BuildEnvOptions{ProjectID: "project1", DiskSizeGb: 2, MachineType: "machine1", Timeout: "128", Concurrency: 2, Profile: "p1", ActivationKubeContext: "gke_test", FailOnActivationConflict: true},
+			modules:            []string{"cfg1_0"},
+			existingActivation: []v1.Activation{{Command: "build"}},
+			expectedErrMsg:     `{"errorCode":"PROFILE_ACTIVATION_CONFLICT","errorMessage":"profile \"p1\" already has different activation conditions; rerun without --fail-on-activation-conflict to merge them"}` + "\n",
+		},
 		{
 			description:  "add to new profile in selected modules",
 			modules:      []string{"cfg1_1"},
@@ -302,6 +505,20 @@ profiles:
 `,
 			},
 		},
+		{
+			description:        "dry run reports a pending change without writing",
+			dryRun:             true,
+			buildEnvOpts:       inspect.BuildEnvOptions{ProjectID: "project1", DiskSizeGb: 2, MachineType: "machine1", Timeout: "128", Concurrency: 2},
+			expectPending:      true,
+			expectDiffContains: "+  googleCloudBuild:",
+		},
+		{
+			description:   "dry run reports no change when nothing is selected for modification",
+			dryRun:        true,
+			modules:       []string{"cfg2"},
+			buildEnvOpts:  inspect.BuildEnvOptions{ProjectID: "project1", DiskSizeGb: 2, MachineType: "machine1", Timeout: "128", Concurrency: 2},
+			expectPending: false,
+		},
 		{
 			description:    "actionable error",
 			err:            sErrors.MainConfigFileNotFoundErr("path/to/skaffold.yaml", fmt.Errorf("failed to read file : %q", "skaffold.yaml")),
@@ -336,6 +553,9 @@ profiles:
 						{Name: "p1", Pipeline: v1.Pipeline{Build: v1.BuildConfig{BuildType: v1.BuildType{LocalBuild: &v1.LocalBuild{}}}}},
 					}}, SourceFile: pathToCfg2, SourceIndex: 0},
 			}
+			if test.existingActivation != nil {
+				configSet[0].SkaffoldConfig.Profiles[0].Activation = test.existingActivation
+			}
 			t.Override(&inspect.ConfigSetFunc, func(opts config.SkaffoldOptions) (parser.SkaffoldConfigSet, error) {
 				if test.err != nil {
 					return nil, test.err
@@ -361,6 +581,9 @@ profiles:
 				t.FailNow()
 				return nil, nil
 			})
+			t.Override(&inspect.BackupWriteFunc, func(filename string, data []byte) error {
+				return nil
+			})
 			var actualCfg1, actualCfg2 string
 			t.Override(&inspect.WriteFileFunc, func(filename string, data []byte) error {
 				switch filename {
@@ -375,9 +598,22 @@ profiles:
 			})
 
 			var buf bytes.Buffer
-			err := AddGcbBuildEnv(context.Background(), &buf, inspect.Options{OutFormat: "json", Modules: test.modules, BuildEnvOptions: test.buildEnvOpts})
+			err := AddGcbBuildEnv(context.Background(), &buf, inspect.Options{OutFormat: "json", Modules: test.modules, DryRun: test.dryRun, BuildEnvOptions: test.buildEnvOpts})
+			if test.dryRun {
+				if test.expectPending {
+					t.CheckDeepEqual(inspect.ErrPendingChanges, err)
+				} else {
+					t.CheckNoError(err)
+				}
+				t.CheckDeepEqual("", actualCfg1)
+				t.CheckDeepEqual("", actualCfg2)
+				if test.expectDiffContains != "" {
+					t.CheckDeepEqual(true, strings.Contains(buf.String(), test.expectDiffContains))
+				}
+				return
+			}
 			t.CheckNoError(err)
-			if test.err == nil {
+			if test.expectedErrMsg == "" {
 				t.CheckDeepEqual(test.expectedConfigs[0], actualCfg1)
 				t.CheckDeepEqual(test.expectedConfigs[1], actualCfg2)
 			} else {
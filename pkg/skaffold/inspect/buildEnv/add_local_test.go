@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/inspect"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/parser"
+	v1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yaml"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestAddLocalBuildEnv(t *testing.T) {
+	testutil.Run(t, "add to default pipeline", func(t *testutil.T) {
+		cfg := &v1.SkaffoldConfig{Metadata: v1.Metadata{Name: "cfg1"}}
+		buildEnvOpts := inspect.BuildEnvOptions{LocalConcurrency: 4, LocalUseDockerCLI: true, LocalUseBuildkit: true, LocalTryImportMissing: true}
+
+		t.Override(&inspect.ConfigSetFunc, func(opts config.SkaffoldOptions) (parser.SkaffoldConfigSet, error) {
+			return parser.SkaffoldConfigSet{
+				&parser.SkaffoldConfigEntry{SkaffoldConfig: cfg, SourceFile: "skaffold.yaml", IsRootConfig: true, SourceIndex: 0},
+			}, nil
+		})
+		t.Override(&inspect.ReadFileFunc, func(filename string) ([]byte, error) {
+			return yaml.MarshalWithSeparator([]*v1.SkaffoldConfig{cfg})
+		})
+		var actual []byte
+		t.Override(&inspect.WriteFileFunc, func(filename string, data []byte) error {
+			actual = data
+			return nil
+		})
+		t.Override(&inspect.BackupWriteFunc, func(filename string, data []byte) error { return nil })
+
+		var buf bytes.Buffer
+		err := AddLocalBuildEnv(context.Background(), &buf, inspect.Options{OutFormat: "json", BuildEnvOptions: buildEnvOpts})
+		t.CheckNoError(err)
+
+		var written v1.SkaffoldConfig
+		t.CheckNoError(yaml.Unmarshal(actual, &written))
+		t.CheckDeepEqual(true, NewLocalSpec(buildEnvOpts).Equal(&written.Pipeline.Build))
+	})
+}
+
+func TestLocalSpecEqual(t *testing.T) {
+	tests := []struct {
+		description string
+		build       *v1.LocalBuild
+		spec        LocalSpec
+		expected    bool
+	}{
+		{
+			description: "matches on every field",
+			build:       &v1.LocalBuild{Concurrency: intPtr(4), UseDockerCLI: true, UseBuildkit: boolPtr(true), TryImportMissing: true},
+			spec:        LocalSpec{Concurrency: 4, UseDockerCLI: true, UseBuildkit: true, TryImportMissing: true},
+			expected:    true,
+		},
+		{
+			description: "differs only in concurrency",
+			build:       &v1.LocalBuild{Concurrency: intPtr(4), UseDockerCLI: true, UseBuildkit: boolPtr(true), TryImportMissing: true},
+			spec:        LocalSpec{Concurrency: 8, UseDockerCLI: true, UseBuildkit: true, TryImportMissing: true},
+			expected:    false,
+		},
+		{
+			description: "differs only in useBuildkit",
+			build:       &v1.LocalBuild{Concurrency: intPtr(4), UseDockerCLI: true, UseBuildkit: boolPtr(false), TryImportMissing: true},
+			spec:        LocalSpec{Concurrency: 4, UseDockerCLI: true, UseBuildkit: true, TryImportMissing: true},
+			expected:    false,
+		},
+		{
+			description: "nil LocalBuild never matches",
+			build:       nil,
+			spec:        LocalSpec{Concurrency: 4, UseDockerCLI: true, UseBuildkit: true, TryImportMissing: true},
+			expected:    false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			build := &v1.BuildConfig{BuildType: v1.BuildType{LocalBuild: test.build}}
+			if actual := test.spec.Equal(build); actual != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int { return &i }
+
+func boolPtr(b bool) *bool { return &b }
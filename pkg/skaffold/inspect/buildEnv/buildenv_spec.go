@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"reflect"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/inspect"
+	v1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+)
+
+// BuildEnvSpec describes a build environment that `inspect build-env` can
+// inject into a module's default build config or into one of its profiles.
+// Concrete implementations keep the profile-cascading logic in AddBuildEnv
+// generic across build types instead of duplicating it per-command.
+type BuildEnvSpec interface {
+	// Kind is the name used on the CLI and in the generated YAML block, e.g. "googleCloudBuild".
+	Kind() string
+	// Apply overwrites build's environment with this spec.
+	Apply(build *v1.BuildConfig)
+	// Equal reports whether build already matches this spec.
+	Equal(build *v1.BuildConfig) bool
+}
+
+// GcbSpec configures Google Cloud Build.
+type GcbSpec struct {
+	ProjectID   string
+	DiskSizeGb  int64
+	MachineType string
+	Timeout     string
+	Concurrency int
+}
+
+// NewGcbSpec builds a GcbSpec from the GCB fields of opts.
+func NewGcbSpec(opts inspect.BuildEnvOptions) GcbSpec {
+	return GcbSpec{
+		ProjectID:   opts.ProjectID,
+		DiskSizeGb:  opts.DiskSizeGb,
+		MachineType: opts.MachineType,
+		Timeout:     opts.Timeout,
+		Concurrency: opts.Concurrency,
+	}
+}
+
+func (s GcbSpec) Kind() string { return "googleCloudBuild" }
+
+func (s GcbSpec) Apply(build *v1.BuildConfig) {
+	build.BuildType = v1.BuildType{GoogleCloudBuild: &v1.GoogleCloudBuild{
+		ProjectID:   s.ProjectID,
+		DiskSizeGb:  s.DiskSizeGb,
+		MachineType: s.MachineType,
+		Timeout:     s.Timeout,
+		Concurrency: s.Concurrency,
+	}}
+}
+
+func (s GcbSpec) Equal(build *v1.BuildConfig) bool {
+	gcb := build.GoogleCloudBuild
+	if gcb == nil {
+		return false
+	}
+	return gcb.ProjectID == s.ProjectID &&
+		gcb.DiskSizeGb == s.DiskSizeGb &&
+		gcb.MachineType == s.MachineType &&
+		gcb.Timeout == s.Timeout &&
+		gcb.Concurrency == s.Concurrency
+}
+
+// NewLocalSpec builds a LocalSpec from the local build fields of opts.
+func NewLocalSpec(opts inspect.BuildEnvOptions) LocalSpec {
+	return LocalSpec{
+		Concurrency:      opts.LocalConcurrency,
+		UseDockerCLI:     opts.LocalUseDockerCLI,
+		UseBuildkit:      opts.LocalUseBuildkit,
+		TryImportMissing: opts.LocalTryImportMissing,
+	}
+}
+
+// LocalSpec configures a local Docker build.
+type LocalSpec struct {
+	Concurrency      int
+	UseDockerCLI     bool
+	UseBuildkit      bool
+	TryImportMissing bool
+}
+
+func (s LocalSpec) Kind() string { return "local" }
+
+func (s LocalSpec) Apply(build *v1.BuildConfig) {
+	concurrency := s.Concurrency
+	build.BuildType = v1.BuildType{LocalBuild: &v1.LocalBuild{
+		Concurrency:      &concurrency,
+		UseDockerCLI:     s.UseDockerCLI,
+		UseBuildkit:      &s.UseBuildkit,
+		TryImportMissing: s.TryImportMissing,
+	}}
+}
+
+func (s LocalSpec) Equal(build *v1.BuildConfig) bool {
+	local := build.LocalBuild
+	if local == nil || local.Concurrency == nil || local.UseBuildkit == nil {
+		return false
+	}
+	return local.UseDockerCLI == s.UseDockerCLI &&
+		local.TryImportMissing == s.TryImportMissing &&
+		*local.Concurrency == s.Concurrency &&
+		*local.UseBuildkit == s.UseBuildkit
+}
+
+// NewClusterSpec builds a ClusterSpec from the cluster build fields of opts.
+func NewClusterSpec(opts inspect.BuildEnvOptions) ClusterSpec {
+	return ClusterSpec{
+		Namespace: opts.ClusterNamespace,
+		Timeout:   opts.ClusterTimeout,
+	}
+}
+
+// ClusterSpec configures an in-cluster (Kaniko) build.
+type ClusterSpec struct {
+	Namespace    string
+	DockerConfig *v1.DockerConfig
+	Timeout      string
+}
+
+func (s ClusterSpec) Kind() string { return "cluster" }
+
+func (s ClusterSpec) Apply(build *v1.BuildConfig) {
+	build.BuildType = v1.BuildType{Cluster: &v1.ClusterDetails{
+		Namespace:    s.Namespace,
+		DockerConfig: s.DockerConfig,
+		Timeout:      s.Timeout,
+	}}
+}
+
+func (s ClusterSpec) Equal(build *v1.BuildConfig) bool {
+	cluster := build.Cluster
+	if cluster == nil {
+		return false
+	}
+	return cluster.Namespace == s.Namespace && cluster.Timeout == s.Timeout &&
+		reflect.DeepEqual(cluster.DockerConfig, s.DockerConfig)
+}
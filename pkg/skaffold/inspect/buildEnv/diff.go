@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/inspect"
+)
+
+// fileDiff is the JSON shape emitted for a single file when
+// Options.DiffFormat is "json".
+type fileDiff struct {
+	File    string `json:"file"`
+	Changed bool   `json:"changed"`
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+}
+
+// writeDryRunPreview reports the pending change for file to out, in the
+// format requested by diffFormat. Only the JSON format reports an unchanged
+// file (with "changed": false); the YAML and unified formats print nothing
+// for it.
+func writeDryRunPreview(out io.Writer, diffFormat, file string, before, after []byte) error {
+	changed := string(before) != string(after)
+
+	switch diffFormat {
+	case inspect.DiffFormatJSON:
+		data, err := json.Marshal(fileDiff{File: file, Changed: changed, Before: string(before), After: string(after)})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+
+	case inspect.DiffFormatYAML:
+		if !changed {
+			return nil
+		}
+		_, err := fmt.Fprintf(out, "# %s\n%s", file, after)
+		return err
+
+	default: // "unified", or unset
+		if !changed {
+			return nil
+		}
+		_, err := fmt.Fprint(out, unifiedDiff(file, before, after))
+		return err
+	}
+}
+
+// unifiedDiff renders a minimal unified diff of before -> after, line by
+// line, good enough for previewing the small, structured changes `inspect
+// build-env` makes to a skaffold.yaml.
+func unifiedDiff(file string, before, after []byte) string {
+	beforeLines := strings.Split(string(before), "\n")
+	afterLines := strings.Split(string(after), "\n")
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", file, file)
+
+	common := lcs(beforeLines, afterLines)
+	bi, ai, ci := 0, 0, 0
+	for bi < len(beforeLines) || ai < len(afterLines) {
+		switch {
+		case ci < len(common) && bi < len(beforeLines) && ai < len(afterLines) &&
+			beforeLines[bi] == common[ci] && afterLines[ai] == common[ci]:
+			fmt.Fprintf(&sb, " %s\n", beforeLines[bi])
+			bi++
+			ai++
+			ci++
+		case bi < len(beforeLines) && (ci >= len(common) || beforeLines[bi] != common[ci]):
+			fmt.Fprintf(&sb, "-%s\n", beforeLines[bi])
+			bi++
+		default:
+			fmt.Fprintf(&sb, "+%s\n", afterLines[ai])
+			ai++
+		}
+	}
+	return sb.String()
+}
+
+// lcs returns the longest common subsequence of lines shared by a and b.
+func lcs(a, b []string) []string {
+	n, m := len(a), len(b)
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, a[i])
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/inspect"
+	v1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+)
+
+// ModuleDrift reports, for a single module, how its configured build
+// environment compares to the one Skaffold actually used the last time it
+// built that module.
+type ModuleDrift struct {
+	Module           string                 `json:"module"`
+	ConfiguredKind   string                 `json:"configuredKind"`
+	ConfiguredFields map[string]interface{} `json:"configuredFields,omitempty"`
+	LastBuiltKind    string                 `json:"lastBuiltKind,omitempty"`
+	LastBuiltFields  map[string]interface{} `json:"lastBuiltFields,omitempty"`
+	Drifted          bool                   `json:"drifted"`
+	Diff             []string               `json:"diff,omitempty"`
+}
+
+// DriftBuildEnv is a read-only counterpart to AddBuildEnv: it resolves the
+// effective build environment for every selected module, root or not (the
+// build config of the named opts.BuildEnvOptions.Profile where the module has
+// that profile, its default build config otherwise), then compares it against the build environment Skaffold last actually used
+// for that module, so CI can catch a locally-edited build env that was never
+// rebuilt with, without having to run `skaffold build`.
+func DriftBuildEnv(ctx context.Context, out io.Writer, opts inspect.Options) error {
+	configSet, err := inspect.ConfigSetFunc(config.SkaffoldOptions{ConfigurationFilter: opts.Modules})
+	if err != nil {
+		return inspect.OutputInspectError(out, err)
+	}
+
+	profile := opts.BuildEnvOptions.Profile
+	report := []ModuleDrift{}
+	for _, entry := range configSet {
+		cfg := entry.SkaffoldConfig
+		build := &cfg.Pipeline.Build
+		if profile != "" {
+			for i := range cfg.Profiles {
+				if cfg.Profiles[i].Name == profile {
+					build = &cfg.Profiles[i].Pipeline.Build
+					break
+				}
+			}
+		}
+
+		kind, fields, err := describeBuild(build)
+		if err != nil {
+			return inspect.OutputInspectError(out, err)
+		}
+
+		d := ModuleDrift{Module: cfg.Metadata.Name, ConfiguredKind: kind, ConfiguredFields: fields}
+		lastBuilt, found, err := inspect.LastBuiltEnvFunc(cfg.Metadata.Name)
+		if err != nil {
+			return inspect.OutputInspectError(out, err)
+		}
+		if found {
+			d.LastBuiltKind = lastBuilt.Kind
+			d.LastBuiltFields = lastBuilt.Fields
+			d.Diff = diffFields(fields, lastBuilt.Fields)
+			d.Drifted = kind != lastBuilt.Kind || len(d.Diff) > 0
+		}
+		report = append(report, d)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return inspect.OutputInspectError(out, err)
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// describeBuild returns the kind and a generic field map for whichever
+// build type is set on build, by round-tripping it through JSON.
+func describeBuild(build *v1.BuildConfig) (string, map[string]interface{}, error) {
+	var kind string
+	var v interface{}
+	switch {
+	case build.GoogleCloudBuild != nil:
+		kind, v = "googleCloudBuild", build.GoogleCloudBuild
+	case build.LocalBuild != nil:
+		kind, v = "local", build.LocalBuild
+	case build.Cluster != nil:
+		kind, v = "cluster", build.Cluster
+	default:
+		return "", nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", nil, err
+	}
+	return kind, fields, nil
+}
+
+// diffFields reports, for every field that differs between configured and
+// lastBuilt, a "field: lastBuilt -> configured" entry, sorted by field name
+// so the report is deterministic across runs.
+func diffFields(configured, lastBuilt map[string]interface{}) []string {
+	changed := map[string]bool{}
+	for k, cv := range configured {
+		if lv, ok := lastBuilt[k]; !ok || !reflect.DeepEqual(cv, lv) {
+			changed[k] = true
+		}
+	}
+	for k := range lastBuilt {
+		if _, ok := configured[k]; !ok {
+			changed[k] = true
+		}
+	}
+
+	keys := make([]string, 0, len(changed))
+	for k := range changed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var diff []string
+	for _, k := range keys {
+		if cv, ok := configured[k]; ok {
+			diff = append(diff, fmt.Sprintf("%s: %v -> %v", k, lastBuilt[k], cv))
+		} else {
+			diff = append(diff, fmt.Sprintf("%s: %v -> <removed>", k, lastBuilt[k]))
+		}
+	}
+	return diff
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/inspect"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/parser"
+	v1 "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest/v1"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestDriftBuildEnv(t *testing.T) {
+	tests := []struct {
+		description  string
+		lastBuilt    map[string]inspect.LastBuiltEnv
+		expectedJSON string
+	}{
+		{
+			description: "no drift when fields match",
+			lastBuilt: map[string]inspect.LastBuiltEnv{
+				"cfg1": {Kind: "googleCloudBuild", Fields: map[string]interface{}{"machineType": "n1-standard-1"}},
+			},
+			expectedJSON: `[{"module":"cfg1","configuredKind":"googleCloudBuild","configuredFields":{"machineType":"n1-standard-1"},"lastBuiltKind":"googleCloudBuild","lastBuiltFields":{"machineType":"n1-standard-1"},"drifted":false}]` + "\n",
+		},
+		{
+			description: "drift when a field was edited locally",
+			lastBuilt: map[string]inspect.LastBuiltEnv{
+				"cfg1": {Kind: "googleCloudBuild", Fields: map[string]interface{}{"machineType": "n1-standard-4"}},
+			},
+			expectedJSON: `[{"module":"cfg1","configuredKind":"googleCloudBuild","configuredFields":{"machineType":"n1-standard-1"},"lastBuiltKind":"googleCloudBuild","lastBuiltFields":{"machineType":"n1-standard-4"},"drifted":true,"diff":["machineType: n1-standard-4 -> n1-standard-1"]}]` + "\n",
+		},
+		{
+			description:  "no last build recorded yet",
+			lastBuilt:    map[string]inspect.LastBuiltEnv{},
+			expectedJSON: `[{"module":"cfg1","configuredKind":"googleCloudBuild","configuredFields":{"machineType":"n1-standard-1"},"drifted":false}]` + "\n",
+		},
+	}
+	for _, test := range tests {
+		testutil.Run(t, test.description, func(t *testutil.T) {
+			t.Override(&inspect.ConfigSetFunc, func(opts config.SkaffoldOptions) (parser.SkaffoldConfigSet, error) {
+				return parser.SkaffoldConfigSet{
+					&parser.SkaffoldConfigEntry{SkaffoldConfig: &v1.SkaffoldConfig{
+						Metadata: v1.Metadata{Name: "cfg1"},
+						Pipeline: v1.Pipeline{Build: v1.BuildConfig{BuildType: v1.BuildType{GoogleCloudBuild: &v1.GoogleCloudBuild{MachineType: "n1-standard-1"}}}},
+					}, SourceFile: "skaffold.yaml", IsRootConfig: true, SourceIndex: 0},
+				}, nil
+			})
+			t.Override(&inspect.LastBuiltEnvFunc, func(module string) (inspect.LastBuiltEnv, bool, error) {
+				env, ok := test.lastBuilt[module]
+				return env, ok, nil
+			})
+
+			var buf bytes.Buffer
+			err := DriftBuildEnv(context.Background(), &buf, inspect.Options{OutFormat: "json"})
+			t.CheckNoError(err)
+			t.CheckDeepEqual(test.expectedJSON, buf.String())
+		})
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrPendingChanges is returned by a dry-run mutation when it found at least
+// one change it would otherwise have written, so that callers can map it to
+// a `terraform plan`-style exit code (1 = pending change, 0 = no change).
+var ErrPendingChanges = errors.New("inspect: dry run found pending changes")
+
+// unknownErrCode is reported for any error that doesn't opt into a more
+// specific code via statusCoder.
+const unknownErrCode = "INSPECT_UNKNOWN_ERR"
+
+// statusCoder is implemented by skaffold's actionable errors (see
+// pkg/skaffold/schema/errors) to expose a machine-readable error code.
+type statusCoder interface {
+	StatusCode() fmt.Stringer
+}
+
+// errEnvelope is the JSON shape written to stdout when an `inspect`
+// subcommand fails, so that scripts can consume a structured error instead
+// of scraping stderr.
+type errEnvelope struct {
+	ErrorCode    string `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// OutputInspectError writes err to out as an errEnvelope. Subcommands call
+// this instead of returning err directly so that every failure, expected or
+// not, is reported through the same machine-readable channel.
+func OutputInspectError(out io.Writer, err error) error {
+	code := unknownErrCode
+	if sc, ok := err.(statusCoder); ok {
+		code = sc.StatusCode().String()
+	}
+	data, mErr := json.Marshal(errEnvelope{ErrorCode: code, ErrorMessage: err.Error()})
+	if mErr != nil {
+		return mErr
+	}
+	_, wErr := fmt.Fprintln(out, string(data))
+	return wErr
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/parser"
+)
+
+// Options holds the flag values shared by every `skaffold inspect` subcommand.
+type Options struct {
+	Modules   []string
+	OutFormat string
+
+	// DryRun previews a mutating subcommand's changes instead of writing them.
+	DryRun bool
+	// DiffFormat selects how a dry-run preview is rendered: "unified", "yaml" or "json".
+	DiffFormat string
+
+	BuildEnvOptions BuildEnvOptions
+}
+
+// Supported Options.DiffFormat values.
+const (
+	DiffFormatUnified = "unified"
+	DiffFormatYAML    = "yaml"
+	DiffFormatJSON    = "json"
+)
+
+// BuildEnvOptions holds the flag values specific to `skaffold inspect build-env` subcommands.
+type BuildEnvOptions struct {
+	// Profile is the name of the profile to modify. An empty value targets the
+	// default build config of every selected module instead.
+	Profile string
+
+	// GCB fields.
+	ProjectID   string
+	DiskSizeGb  int64
+	MachineType string
+	Timeout     string
+	Concurrency int
+
+	// Local build fields.
+	LocalConcurrency      int
+	LocalUseDockerCLI     bool
+	LocalUseBuildkit      bool
+	LocalTryImportMissing bool
+
+	// Cluster build fields.
+	ClusterNamespace string
+	ClusterTimeout   string
+
+	// ActivationEnv, ActivationKubeContext and ActivationCommand set the
+	// activation conditions of a profile created by a build-env subcommand,
+	// the same way a hand-written profile's `activation` block would.
+	ActivationEnv         map[string]string
+	ActivationKubeContext string
+	ActivationCommand     string
+
+	// FailOnActivationConflict rejects modifying an existing profile whose
+	// activation conditions differ from the ones requested here, instead of
+	// merging the two (the default).
+	FailOnActivationConflict bool
+}
+
+// ConfigSetFunc loads the effective set of Skaffold configs for the given options.
+// It's stubbed out in tests.
+var ConfigSetFunc = parser.GetConfigSet
+
+// ReadFileFunc reads a config file from disk. It's stubbed out in tests.
+var ReadFileFunc = ioutil.ReadFile
+
+// WriteFileFunc writes a config file to disk. It's stubbed out in tests.
+//
+// The write is atomic: data lands in a temporary file alongside filename
+// first, which is fsynced and then renamed over filename, so a crash
+// mid-write never leaves filename partially written.
+var WriteFileFunc = func(filename string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// RemoveFileFunc deletes a config file from disk. It's stubbed out in
+// tests; kept separate from WriteFileFunc so a Transaction rollback can
+// delete a file it created without a caller having to special-case it.
+var RemoveFileFunc = os.Remove
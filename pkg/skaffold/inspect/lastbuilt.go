@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import "encoding/json"
+
+// LastBuiltEnv is the build environment Skaffold actually used the last
+// time it built a given module.
+type LastBuiltEnv struct {
+	Kind   string                 `json:"kind"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+// lastBuiltEnvFile is where RecordBuildEnv persists the build env Skaffold
+// used for each module, keyed by module name.
+const lastBuiltEnvFile = ".skaffold/build-env.json"
+
+// LastBuiltEnvFunc loads the build environment Skaffold last used for
+// module, if any. It's a variable so tests (and `inspect build-env drift`)
+// can stub it out; the default reads the cache RecordBuildEnv writes,
+// reporting nothing found for a module that cache has no entry for yet.
+var LastBuiltEnvFunc = func(module string) (LastBuiltEnv, bool, error) {
+	data, err := ReadFileFunc(lastBuiltEnvFile)
+	if err != nil {
+		return LastBuiltEnv{}, false, nil
+	}
+	var cache map[string]LastBuiltEnv
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return LastBuiltEnv{}, false, err
+	}
+	env, ok := cache[module]
+	return env, ok, nil
+}
+
+// RecordBuildEnv persists the build environment Skaffold just used for
+// module into lastBuiltEnvFile, so a later `inspect build-env drift` run
+// has a real record to compare the configured build env against instead
+// of always reporting "no last build recorded". It's the write side of
+// LastBuiltEnvFunc: once `skaffold build` calls it after a successful
+// build, drift detection reflects what was actually built.
+func RecordBuildEnv(module, kind string, fields map[string]interface{}) error {
+	cache := map[string]LastBuiltEnv{}
+	if data, err := ReadFileFunc(lastBuiltEnvFile); err == nil {
+		if err := json.Unmarshal(data, &cache); err != nil {
+			return err
+		}
+	}
+	cache[module] = LastBuiltEnv{Kind: kind, Fields: fields}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return WriteFileFunc(lastBuiltEnvFile, data)
+}
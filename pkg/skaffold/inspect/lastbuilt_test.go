@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestLastBuiltEnvFuncReadsRecordedEnv(t *testing.T) {
+	testutil.Run(t, "reads back what RecordBuildEnv wrote", func(t *testutil.T) {
+		disk := map[string][]byte{}
+		t.Override(&ReadFileFunc, func(filename string) ([]byte, error) {
+			data, ok := disk[filename]
+			if !ok {
+				return nil, errors.New("no such file")
+			}
+			return data, nil
+		})
+		t.Override(&WriteFileFunc, func(filename string, data []byte) error {
+			disk[filename] = data
+			return nil
+		})
+
+		if err := RecordBuildEnv("cfg1", "googleCloudBuild", map[string]interface{}{"machineType": "n1-standard-1"}); err != nil {
+			t.Fatal(err)
+		}
+
+		env, found, err := LastBuiltEnvFunc("cfg1")
+		t.CheckNoError(err)
+		t.CheckDeepEqual(true, found)
+		t.CheckDeepEqual("googleCloudBuild", env.Kind)
+		t.CheckDeepEqual(map[string]interface{}{"machineType": "n1-standard-1"}, env.Fields)
+	})
+
+	testutil.Run(t, "reports nothing found for a module never recorded", func(t *testutil.T) {
+		disk := map[string][]byte{
+			lastBuiltEnvFile: []byte(`{"cfg1":{"kind":"googleCloudBuild","fields":{"machineType":"n1-standard-1"}}}`),
+		}
+		t.Override(&ReadFileFunc, func(filename string) ([]byte, error) {
+			data, ok := disk[filename]
+			if !ok {
+				return nil, errors.New("no such file")
+			}
+			return data, nil
+		})
+
+		_, found, err := LastBuiltEnvFunc("cfg2")
+		t.CheckNoError(err)
+		t.CheckDeepEqual(false, found)
+	})
+
+	testutil.Run(t, "reports nothing found when the cache file doesn't exist", func(t *testutil.T) {
+		t.Override(&ReadFileFunc, func(filename string) ([]byte, error) {
+			return nil, errors.New("no such file")
+		})
+
+		_, found, err := LastBuiltEnvFunc("cfg1")
+		t.CheckNoError(err)
+		t.CheckDeepEqual(false, found)
+	})
+}
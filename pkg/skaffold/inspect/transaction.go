@@ -0,0 +1,170 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// backupRoot is where Transaction snapshots original file content, so a
+// failed mutation can be inspected or restored after the fact with
+// `inspect backups list|restore`.
+const backupRoot = ".skaffold-backup"
+
+// BackupWriteFunc persists a transaction's snapshot of a file to disk. It's
+// a variable, and deliberately separate from WriteFileFunc, so tests can
+// stub out the target-file writes a mutation makes without also having to
+// account for backup snapshots.
+var BackupWriteFunc = func(filename string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// Transaction buffers the files an `inspect` mutation touches, snapshotting
+// each one's original content into backupRoot the first time it's read or
+// written. Nothing reaches WriteFileFunc until Commit, and if any write in a
+// Commit fails, every file already written during that Commit is restored
+// to its pre-transaction state before the error is returned - a caller
+// never observes a half-modified tree. A path that didn't exist on disk
+// before the transaction is tracked as such, so rolling it back deletes it
+// instead of leaving an empty file behind.
+type Transaction struct {
+	id        string
+	originals map[string][]byte
+	existed   map[string]bool
+	pending   map[string][]byte
+	order     []string
+}
+
+// NewTransaction starts a transaction identified by a short id derived from
+// the current time, used to namespace its backup directory.
+func NewTransaction() *Transaction {
+	sum := sha256.Sum256([]byte(time.Now().String()))
+	return &Transaction{
+		id:        hex.EncodeToString(sum[:])[:12],
+		originals: map[string][]byte{},
+		existed:   map[string]bool{},
+		pending:   map[string][]byte{},
+	}
+}
+
+// ID returns the transaction's backup directory name, e.g. to report to the
+// user where a failed mutation's snapshots can be found.
+func (t *Transaction) ID() string {
+	return t.id
+}
+
+// Read returns path's current content - the buffered write if Write has
+// already been called for it this transaction, otherwise its on-disk
+// content - snapshotting it as this transaction's backup of path the first
+// time it's read.
+func (t *Transaction) Read(path string) ([]byte, error) {
+	if data, ok := t.pending[path]; ok {
+		return data, nil
+	}
+	if data, ok := t.originals[path]; ok {
+		return data, nil
+	}
+	data, err := ReadFileFunc(path)
+	if err != nil {
+		if _, ok := t.existed[path]; !ok {
+			t.existed[path] = false
+		}
+		return nil, err
+	}
+	if err := t.snapshot(path, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Write buffers data for path. Nothing touches disk until Commit.
+func (t *Transaction) Write(path string, data []byte) error {
+	if _, ok := t.existed[path]; !ok {
+		if orig, err := ReadFileFunc(path); err == nil {
+			if err := t.snapshot(path, orig); err != nil {
+				return err
+			}
+		} else {
+			t.existed[path] = false
+		}
+	}
+	if _, ok := t.pending[path]; !ok {
+		t.order = append(t.order, path)
+	}
+	t.pending[path] = data
+	return nil
+}
+
+func (t *Transaction) snapshot(path string, data []byte) error {
+	t.originals[path] = data
+	t.existed[path] = true
+	return BackupWriteFunc(filepath.Join(backupRoot, t.id, path), data)
+}
+
+// Commit writes every buffered file via WriteFileFunc, in the order Write
+// was first called for each. If a write fails, Commit rolls back every file
+// it already wrote during this call to its pre-transaction content and
+// returns the original error.
+func (t *Transaction) Commit() error {
+	var written []string
+	for _, path := range t.order {
+		if err := WriteFileFunc(path, t.pending[path]); err != nil {
+			if rerr := t.rollback(written); rerr != nil {
+				return fmt.Errorf("%w (rollback also failed: %v)", err, rerr)
+			}
+			return err
+		}
+		written = append(written, path)
+	}
+	return nil
+}
+
+// Rollback restores every file this transaction has touched to its
+// pre-transaction state: original content if it existed, deleted if it
+// didn't.
+func (t *Transaction) Rollback() error {
+	paths := make([]string, 0, len(t.existed))
+	for path := range t.existed {
+		paths = append(paths, path)
+	}
+	return t.rollback(paths)
+}
+
+func (t *Transaction) rollback(paths []string) error {
+	var firstErr error
+	for _, path := range paths {
+		var err error
+		if t.existed[path] {
+			err = WriteFileFunc(path, t.originals[path])
+		} else if rerr := RemoveFileFunc(path); rerr != nil && !os.IsNotExist(rerr) {
+			err = rerr
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
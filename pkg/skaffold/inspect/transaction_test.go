@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inspect
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestTransactionCommitRollsBackOnFailure(t *testing.T) {
+	testutil.Run(t, "rollback on second write failure", func(t *testutil.T) {
+		disk := map[string][]byte{
+			"file1": []byte("original1"),
+			"file2": []byte("original2"),
+		}
+		t.Override(&ReadFileFunc, func(filename string) ([]byte, error) {
+			return disk[filename], nil
+		})
+		var writes []string
+		t.Override(&WriteFileFunc, func(filename string, data []byte) error {
+			writes = append(writes, filename)
+			if filename == "file2" && string(data) == "updated2" {
+				return errors.New("disk full")
+			}
+			disk[filename] = data
+			return nil
+		})
+		t.Override(&BackupWriteFunc, func(filename string, data []byte) error { return nil })
+
+		tx := NewTransaction()
+		if _, err := tx.Read("file1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Read("file2"); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Write("file1", []byte("updated1")); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Write("file2", []byte("updated2")); err != nil {
+			t.Fatal(err)
+		}
+
+		err := tx.Commit()
+		t.CheckError(true, err)
+		// file1 was written, then rolled back to its original content.
+		t.CheckDeepEqual([]byte("original1"), disk["file1"])
+		// file2's write never succeeded in the first place.
+		t.CheckDeepEqual([]byte("original2"), disk["file2"])
+	})
+}
+
+func TestTransactionCommitDeletesNeverExistedFileOnRollback(t *testing.T) {
+	testutil.Run(t, "rollback deletes a file that never existed on disk", func(t *testutil.T) {
+		disk := map[string][]byte{
+			"file2": []byte("original2"),
+		}
+		t.Override(&ReadFileFunc, func(filename string) ([]byte, error) {
+			data, ok := disk[filename]
+			if !ok {
+				return nil, os.ErrNotExist
+			}
+			return data, nil
+		})
+		var writes []string
+		t.Override(&WriteFileFunc, func(filename string, data []byte) error {
+			writes = append(writes, filename)
+			if filename == "file2" && string(data) == "updated2" {
+				return errors.New("disk full")
+			}
+			disk[filename] = data
+			return nil
+		})
+		var removed []string
+		t.Override(&RemoveFileFunc, func(filename string) error {
+			removed = append(removed, filename)
+			delete(disk, filename)
+			return nil
+		})
+		t.Override(&BackupWriteFunc, func(filename string, data []byte) error { return nil })
+
+		tx := NewTransaction()
+		// file1 never existed before this transaction.
+		if err := tx.Write("file1", []byte("new1")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Read("file2"); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Write("file2", []byte("updated2")); err != nil {
+			t.Fatal(err)
+		}
+
+		err := tx.Commit()
+		t.CheckError(true, err)
+		// file1 never existed before the transaction, so rollback deletes it
+		// instead of writing an empty file over it.
+		t.CheckDeepEqual([]string{"file1"}, removed)
+		if _, ok := disk["file1"]; ok {
+			t.Fatal("expected file1 to be removed by rollback, but it's still present")
+		}
+	})
+}